@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var db *sql.DB
+
+func initDB() {
+	var err error
+	db, err = sql.Open("sqlite3", "./chess.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS lobbies (
+		passphrase TEXT PRIMARY KEY,
+		seats TEXT NOT NULL,
+		fen TEXT NOT NULL,
+		last_move TEXT NOT NULL,
+		move_history TEXT NOT NULL,
+		game_outcome TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS tournaments (
+		id TEXT PRIMARY KEY,
+		providers TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS tournament_games (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tournament_id TEXT NOT NULL,
+		white TEXT NOT NULL,
+		black TEXT NOT NULL,
+		outcome TEXT NOT NULL,
+		move_count INTEGER NOT NULL,
+		pgn TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS provider_ratings (
+		provider TEXT PRIMARY KEY,
+		rating REAL NOT NULL DEFAULT 1200,
+		wins INTEGER NOT NULL DEFAULT 0,
+		losses INTEGER NOT NULL DEFAULT 0,
+		draws INTEGER NOT NULL DEFAULT 0,
+		games INTEGER NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
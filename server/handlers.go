@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type createLobbyRequest struct {
+	WhiteSeat seatRequest `json:"whiteSeat"`
+	BlackSeat seatRequest `json:"blackSeat"`
+}
+
+type seatRequest struct {
+	Kind     SeatKind `json:"kind"`
+	Provider string   `json:"provider,omitempty"`
+}
+
+type createLobbyResponse struct {
+	Passphrase  string `json:"passphrase"`
+	PlayerToken string `json:"playerToken,omitempty"`
+}
+
+func handleCreateLobby(c *fiber.Ctx) error {
+	var req createLobbyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	whiteSeat, err := toSeat(req.WhiteSeat)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	blackSeat, err := toSeat(req.BlackSeat)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	lobby, err := lobbies.CreateLobby(whiteSeat, blackSeat)
+	if err != nil {
+		logger.Error("error creating lobby", "error", err)
+		return fiber.ErrInternalServerError
+	}
+
+	resp := createLobbyResponse{Passphrase: lobby.Passphrase}
+
+	// if the creator is taking a human seat themselves, claim it immediately
+	// so they don't have to call join right after create.
+	if whiteSeat.Kind == SeatHuman || blackSeat.Kind == SeatHuman {
+		token, err := lobby.Join()
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+		resp.PlayerToken = token
+	}
+
+	go lobby.playLobby()
+
+	return c.JSON(resp)
+}
+
+func toSeat(req seatRequest) (*Seat, error) {
+	switch req.Kind {
+	case SeatAI:
+		if _, err := getProvider(req.Provider); err != nil {
+			return nil, err
+		}
+		return &Seat{Kind: SeatAI, Provider: req.Provider}, nil
+	case SeatHuman:
+		return &Seat{Kind: SeatHuman}, nil
+	default:
+		return nil, fmt.Errorf("unknown seat kind: %s", req.Kind)
+	}
+}
+
+func handleJoinLobby(c *fiber.Ctx) error {
+	phrase := c.Params("phrase")
+	lobby, ok := lobbies.Get(phrase)
+	if !ok {
+		return fiber.ErrNotFound
+	}
+
+	token, err := lobby.Join()
+	if err != nil {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+
+	return c.JSON(fiber.Map{"playerToken": token})
+}
+
+type submitMoveRequest struct {
+	PlayerToken string `json:"playerToken"`
+	San         string `json:"san"`
+}
+
+func handleSubmitMove(c *fiber.Ctx) error {
+	phrase := c.Params("phrase")
+	lobby, ok := lobbies.Get(phrase)
+	if !ok {
+		return fiber.ErrNotFound
+	}
+
+	var req submitMoveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	if err := lobby.SubmitMove(req.PlayerToken, req.San); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func init() {
+	Register(&openAIProvider{})
+	Register(&anthropicProvider{})
+}
+
+func movePrompt(fen string, moveHistory []string, retryHint string) string {
+	retryMessage := ""
+	if retryHint != "" {
+		retryMessage = fmt.Sprintf("Your previous move '%s' was invalid. Please try again with a valid move. ", retryHint)
+	}
+	return fmt.Sprintf(`%sYou are playing a game of chess. The current board state in FEN notation is:
+%s
+
+The move history (in algebraic notation) is:
+%s
+
+Please provide your next move in standard algebraic notation (e.g., "e4", "Nf3", "O-O").
+Your move must be legal according to the current board state and chess rules.
+Respond with only the move, nothing else.`, retryMessage, fen, strings.Join(moveHistory, ", "))
+}
+
+type openAIProvider struct{}
+
+func (p *openAIProvider) Name() string { return "openai" }
+
+func (p *openAIProvider) Move(ctx context.Context, fen string, moveHistory []string, retryHint string) (string, error) {
+	prompt := movePrompt(fen, moveHistory, retryHint)
+
+	resp, err := openAIClient.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: openai.GPT4,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: "You are an expert chess player. Provide only valid chess moves in standard algebraic notation."},
+				{Role: openai.ChatMessageRoleUser, Content: prompt},
+			},
+			MaxTokens: 10,
+		},
+	)
+
+	if err != nil {
+		return "", err
+	}
+
+	move := strings.TrimSpace(resp.Choices[0].Message.Content)
+	return move, nil
+}
+
+type anthropicProvider struct{}
+
+func (p *anthropicProvider) Name() string { return "anthropic" }
+
+func (p *anthropicProvider) Move(ctx context.Context, fen string, moveHistory []string, retryHint string) (string, error) {
+	url := "https://api.anthropic.com/v1/messages"
+	prompt := movePrompt(fen, moveHistory, retryHint)
+
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model":      "claude-3-opus-20240229",
+		"max_tokens": 10,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error marshaling request body: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", os.Getenv("ANTHROPIC_API_KEY"))
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var anthropicResp struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	err = json.Unmarshal(body, &anthropicResp)
+	if err != nil {
+		return "", fmt.Errorf("error unmarshaling response: %v", err)
+	}
+
+	if len(anthropicResp.Content) == 0 || anthropicResp.Content[0].Text == "" {
+		return "", fmt.Errorf("empty completion from Anthropic")
+	}
+
+	move := strings.TrimSpace(anthropicResp.Content[0].Text)
+	return move, nil
+}
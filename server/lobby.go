@@ -0,0 +1,763 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+// errPlayLoopAborted is returned by a move-wait that was cut short because
+// playLobby moved on without it (lock lost, or a control message preempted
+// the seat whose move it was waiting on). The caller already stopped
+// listening for a result by the time this is returned, so the value itself
+// is never inspected — it just lets the aborted goroutine return instead of
+// blocking forever.
+var errPlayLoopAborted = errors.New("play loop aborted")
+
+// SeatKind identifies who is occupying a seat at the board.
+type SeatKind string
+
+const (
+	SeatHuman SeatKind = "human"
+	SeatAI    SeatKind = "ai"
+)
+
+// Seat is one of the two colors in a lobby's game. A seat is either held by
+// an AI provider (e.g. "openai", "anthropic") or by a human player, who is
+// identified by a UUID cookie/token handed out on join.
+type Seat struct {
+	Kind     SeatKind `json:"kind"`
+	Provider string   `json:"provider,omitempty"`
+	PlayerID string   `json:"playerId,omitempty"`
+}
+
+// Lobby is a single game table: two seats, its own chess game, its own move
+// history, and its own stream of game-state events. Lobbies are looked up
+// by passphrase, which is what players share to join or reconnect.
+type Lobby struct {
+	ID          string
+	Passphrase  string
+	Seats       [2]*Seat // chess.White, chess.Black
+	Game        *chess.Game
+	MoveHistory []string
+	LastMove    string
+	CreatedAt   time.Time
+
+	events *eventLog
+
+	subsMu sync.Mutex
+	subs   map[chan streamEvent]struct{}
+
+	// mu guards Seats mutation, MoveHistory/LastMove, and every access to
+	// Game: notnil/chess.Game isn't safe for concurrent use, and playLobby
+	// mutates it from its own goroutine while HTTP/WS handlers read it from
+	// theirs.
+	mu      sync.Mutex
+	moveCh  [2]chan string
+	control chan lobbyControlMsg
+
+	// playerLocks serializes moves per player token, so two tabs open to the
+	// same seat can't both win a race into SubmitMove.
+	playerLocks sync.Map
+
+	// driving is 1 while this replica's playLobby goroutine is active for
+	// this lobby. It guards watchForLobbyTakeover from piling up a new
+	// goroutine (and a misleading "another replica already drives this
+	// lobby" log) on every scan tick for a lobby this same replica is
+	// already driving.
+	driving int32
+}
+
+// lobbyControlMsg is an out-of-band instruction to playLobby that isn't a
+// move, e.g. a resignation, which can happen at any point in the game
+// regardless of whose turn it is.
+type lobbyControlMsg struct {
+	kind      string // "resign" or "draw"
+	seatIndex int
+}
+
+// LobbyManager owns every in-memory lobby and guards concurrent access to
+// the lobby map.
+type LobbyManager struct {
+	mu       sync.RWMutex
+	byPhrase map[string]*Lobby
+}
+
+func NewLobbyManager() *LobbyManager {
+	return &LobbyManager{
+		byPhrase: make(map[string]*Lobby),
+	}
+}
+
+// CreateLobby builds a new lobby with the given seat assignments. whiteSeat
+// and blackSeat describe who occupies each color; for an AI seat, provider
+// must be a registered provider name, for a human seat it starts unclaimed.
+func (m *LobbyManager) CreateLobby(whiteSeat, blackSeat *Seat) (*Lobby, error) {
+	passphrase, err := generatePassphrase()
+	if err != nil {
+		return nil, fmt.Errorf("error generating passphrase: %v", err)
+	}
+
+	lobby := &Lobby{
+		ID:          passphrase,
+		Passphrase:  passphrase,
+		Seats:       [2]*Seat{whiteSeat, blackSeat},
+		Game:        chess.NewGame(),
+		MoveHistory: []string{},
+		CreatedAt:   time.Now(),
+		events:      newEventLog(),
+		subs:        make(map[chan streamEvent]struct{}),
+		moveCh:      [2]chan string{make(chan string), make(chan string)},
+		control:     make(chan lobbyControlMsg, 1),
+	}
+
+	m.mu.Lock()
+	m.byPhrase[passphrase] = lobby
+	m.mu.Unlock()
+
+	if err := store.SaveLobby(lobby); err != nil {
+		return nil, err
+	}
+
+	go lobby.watchStore()
+
+	return lobby, nil
+}
+
+// restoreLobby rebuilds a lobby from its persisted seats and move history,
+// replaying every move to reach the same game position.
+func (m *LobbyManager) restoreLobby(passphrase string, seats [2]*Seat, moveHistoryCSV string) (*Lobby, error) {
+	lobby := &Lobby{
+		ID:          passphrase,
+		Passphrase:  passphrase,
+		Seats:       seats,
+		Game:        chess.NewGame(),
+		MoveHistory: []string{},
+		CreatedAt:   time.Now(),
+		events:      newEventLog(),
+		subs:        make(map[chan streamEvent]struct{}),
+		moveCh:      [2]chan string{make(chan string), make(chan string)},
+		control:     make(chan lobbyControlMsg, 1),
+	}
+
+	if moveHistoryCSV != "" {
+		for _, move := range splitCSV(moveHistoryCSV) {
+			if err := lobby.Game.MoveStr(move); err != nil {
+				return nil, fmt.Errorf("replaying move %q: %v", move, err)
+			}
+			lobby.MoveHistory = append(lobby.MoveHistory, move)
+			lobby.LastMove = move
+		}
+	}
+
+	m.mu.Lock()
+	m.byPhrase[passphrase] = lobby
+	m.mu.Unlock()
+
+	go lobby.watchStore()
+
+	return lobby, nil
+}
+
+// loadLobbies restores every unfinished lobby from the store on startup and
+// resumes its play loop. With STORE_BACKEND=redis this also means a replica
+// that restarts (or a second replica that starts up) picks up every lobby
+// still in progress, not just the ones it happened to create itself.
+func loadLobbies(m *LobbyManager) error {
+	snapshots, err := store.LoadOpenLobbies()
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range snapshots {
+		lobby, err := m.restoreLobby(snap.Passphrase, snap.Seats, snap.MoveHistoryCSV)
+		if err != nil {
+			logger.Error("error restoring lobby", "lobby_id", snap.Passphrase, "error", err)
+			continue
+		}
+		go lobby.playLobby()
+	}
+
+	return nil
+}
+
+// lobbyTakeoverScanInterval controls how often a replica re-checks whether
+// any in-progress lobby needs picking up. It doesn't need to be tighter
+// than lobbyLockTTL: a lease can't lapse faster than that, so scanning more
+// often would only mean more wasted AcquireLobbyLock calls that lose.
+const lobbyTakeoverScanInterval = lobbyLockTTL
+
+// watchForLobbyTakeover keeps a live fleet self-healing the way loadLobbies
+// does at startup. loadLobbies only ever runs once, so if the replica
+// driving a lobby dies mid-game, its lease simply expires and nothing picks
+// the game back up unless some replica happens to restart. This re-scans
+// the store on a timer instead: for lobbies this replica already knows
+// about, it retries playLobby (a no-op if another replica still holds the
+// lease, via AcquireLobbyLock failing); for lobbies it doesn't know about
+// yet — created on a different replica after this one started — it
+// restores and starts driving them, the same way startup would have.
+func watchForLobbyTakeover(m *LobbyManager) {
+	ticker := time.NewTicker(lobbyTakeoverScanInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, lobby := range m.All() {
+			if lobby.outcome() == chess.NoOutcome {
+				go lobby.playLobby()
+			}
+		}
+
+		snapshots, err := store.LoadOpenLobbies()
+		if err != nil {
+			logger.Error("error re-scanning open lobbies", "error", err)
+			continue
+		}
+		for _, snap := range snapshots {
+			if _, ok := m.Get(snap.Passphrase); ok {
+				continue
+			}
+			lobby, err := m.restoreLobby(snap.Passphrase, snap.Seats, snap.MoveHistoryCSV)
+			if err != nil {
+				logger.Error("error restoring lobby during takeover scan", "lobby_id", snap.Passphrase, "error", err)
+				continue
+			}
+			go lobby.playLobby()
+		}
+	}
+}
+
+func (m *LobbyManager) Get(passphrase string) (*Lobby, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	lobby, ok := m.byPhrase[passphrase]
+	return lobby, ok
+}
+
+// All returns every lobby this replica currently knows about, for
+// watchForLobbyTakeover's periodic scan.
+func (m *LobbyManager) All() []*Lobby {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]*Lobby, 0, len(m.byPhrase))
+	for _, lobby := range m.byPhrase {
+		out = append(out, lobby)
+	}
+	return out
+}
+
+// Join claims the first open human seat in the lobby and returns the new
+// player's token.
+func (l *Lobby) Join() (string, error) {
+	l.mu.Lock()
+	var token string
+	var claimErr error
+	claimed := false
+	for _, seat := range l.Seats {
+		if seat.Kind == SeatHuman && seat.PlayerID == "" {
+			token, claimErr = newUUID()
+			if claimErr == nil {
+				seat.PlayerID = token
+				claimed = true
+			}
+			break
+		}
+	}
+	l.mu.Unlock()
+
+	if claimErr != nil {
+		return "", claimErr
+	}
+	if !claimed {
+		return "", fmt.Errorf("no open human seat in lobby %s", l.Passphrase)
+	}
+
+	// Persist the claim immediately: otherwise it only reaches the store on
+	// the seat's first move, and a restart in between loses the token and
+	// reopens the seat out from under whoever joined it.
+	if err := store.SaveLobby(l); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// SubmitMove delivers a human player's move to the seat that matches
+// playerToken. It blocks only as long as it takes to hand the move off to
+// playLobby, which is waiting on the seat's move channel.
+func (l *Lobby) SubmitMove(playerToken, san string) error {
+	l.mu.Lock()
+	var seatIndex = -1
+	for i, seat := range l.Seats {
+		if seat.Kind == SeatHuman && seat.PlayerID == playerToken {
+			seatIndex = i
+			break
+		}
+	}
+	if seatIndex == -1 {
+		l.mu.Unlock()
+		return fmt.Errorf("no seat in lobby %s belongs to player", l.Passphrase)
+	}
+
+	turnColor := chess.White
+	if l.Game.Position().Turn() == chess.Black {
+		turnColor = chess.Black
+	}
+	if seatIndex != int(turnColor)-1 {
+		l.mu.Unlock()
+		return fmt.Errorf("it is not this player's turn")
+	}
+
+	// Reject an illegal move here, before handing it to playLobby, so the
+	// caller learns about it immediately instead of watching the board
+	// stall for 5s while playLobby silently re-waits on the same seat.
+	err := validateMove(l.Game, san)
+	l.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("illegal move %q: %v", san, err)
+	}
+
+	select {
+	case l.moveCh[seatIndex] <- san:
+		return nil
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("timed out waiting for lobby to accept move")
+	}
+}
+
+// Resign ends the game in the opposing seat's favor. Unlike a move, it can
+// happen on either player's turn, so it's delivered to playLobby through the
+// control channel instead of the per-seat move channels.
+func (l *Lobby) Resign(playerToken string) error {
+	return l.sendControl(playerToken, "resign")
+}
+
+// OfferDraw ends the game as a draw. There is no accept/decline negotiation
+// yet, so an offer from either seat immediately settles the game as drawn.
+func (l *Lobby) OfferDraw(playerToken string) error {
+	return l.sendControl(playerToken, "draw")
+}
+
+func (l *Lobby) sendControl(playerToken, kind string) error {
+	l.mu.Lock()
+	var seatIndex = -1
+	for i, seat := range l.Seats {
+		if seat.Kind == SeatHuman && seat.PlayerID == playerToken {
+			seatIndex = i
+			break
+		}
+	}
+	l.mu.Unlock()
+
+	if seatIndex == -1 {
+		return fmt.Errorf("no seat in lobby %s belongs to player", l.Passphrase)
+	}
+
+	select {
+	case l.control <- lobbyControlMsg{kind: kind, seatIndex: seatIndex}:
+		return nil
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("timed out waiting for lobby to accept %s", kind)
+	}
+}
+
+// applyControlMsg settles the game according to msg and persists the result.
+// It's only called from playLobby's own goroutine, and takes l.mu around
+// each Game call since handlers read Game from other goroutines.
+func (l *Lobby) applyControlMsg(msg lobbyControlMsg) {
+	switch msg.kind {
+	case "resign":
+		color := chess.White
+		if msg.seatIndex == 1 {
+			color = chess.Black
+		}
+		l.mu.Lock()
+		l.Game.Resign(color)
+		l.mu.Unlock()
+		logger.Info("player resigned", "lobby_id", l.Passphrase, "seat", msg.seatIndex)
+	case "draw":
+		l.mu.Lock()
+		err := l.Game.Draw(chess.DrawOffer)
+		l.mu.Unlock()
+		if err != nil {
+			logger.Warn("error recording draw offer", "lobby_id", l.Passphrase, "error", err)
+			return
+		}
+		logger.Info("game drawn by agreement", "lobby_id", l.Passphrase, "seat", msg.seatIndex)
+	}
+
+	if err := store.SaveLobby(l); err != nil {
+		logger.Error("error persisting lobby after control message", "lobby_id", l.Passphrase, "error", err)
+	}
+}
+
+// playerLock returns a mutex scoped to a single player token, so two
+// WebSocket connections from the same player (e.g. two browser tabs) can't
+// both win a race to submit a move.
+func (l *Lobby) playerLock(playerToken string) *sync.Mutex {
+	actual, _ := l.playerLocks.LoadOrStore(playerToken, &sync.Mutex{})
+	return actual.(*sync.Mutex)
+}
+
+// lobbyLockTTL is the lease duration for driving a lobby's play loop. It
+// must comfortably exceed renewLobbyLockInterval so a slow renewal or one
+// missed tick doesn't cause another replica to steal the lobby mid-game.
+const lobbyLockTTL = 15 * time.Second
+const renewLobbyLockInterval = 5 * time.Second
+
+// playLobby drives a single lobby's game to completion, requesting AI moves
+// for AI-occupied seats and blocking on the seat's move channel for human
+// seats. It mirrors the old global playGame loop, scoped to one lobby.
+//
+// Only one replica may drive a given lobby at a time, so playLobby first
+// acquires a distributed lock and renews it for as long as the game runs;
+// losing the lock (another replica took over after a missed renewal) stops
+// this replica's loop rather than risk two replicas applying moves to the
+// same game.
+func (l *Lobby) playLobby() {
+	if !atomic.CompareAndSwapInt32(&l.driving, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&l.driving, 0)
+
+	acquired, err := store.AcquireLobbyLock(l.Passphrase, processID, lobbyLockTTL)
+	if err != nil {
+		logger.Error("error acquiring lobby lock", "lobby_id", l.Passphrase, "error", err)
+		return
+	}
+	if !acquired {
+		logger.Info("another replica already drives this lobby", "lobby_id", l.Passphrase)
+		return
+	}
+
+	lostLock := make(chan struct{})
+	stopRenew := make(chan struct{})
+	defer close(stopRenew)
+	go l.renewLobbyLockLoop(lostLock, stopRenew)
+
+	seatIndex := 0 // white moves first
+	moveNumber := 0
+
+	for l.outcome() == chess.NoOutcome {
+		seat := l.Seats[seatIndex]
+
+		type moveResult struct {
+			move string
+			err  error
+		}
+		results := make(chan moveResult, 1)
+		abort := make(chan struct{})
+		go func() {
+			if seat.Kind == SeatAI {
+				move, err := l.getAIMove(seat)
+				results <- moveResult{move, err}
+			} else {
+				move, err := l.waitForHumanMove(seatIndex, abort)
+				results <- moveResult{move, err}
+			}
+		}()
+
+		var move string
+		var err error
+		select {
+		case <-lostLock:
+			// Unblock a waitForHumanMove still parked on moveCh with no
+			// sender left — we're returning without reading results, so
+			// nothing else would ever wake it.
+			close(abort)
+			logger.Warn("lost lobby lock, stopping play loop", "lobby_id", l.Passphrase)
+			return
+		case msg := <-l.control:
+			close(abort)
+			l.applyControlMsg(msg)
+			l.broadcast()
+			continue
+		case res := <-results:
+			move, err = res.move, res.err
+		}
+
+		if err != nil {
+			logger.Error("error getting move", "lobby_id", l.Passphrase, "seat", seatIndex, "move_number", moveNumber+1, "error", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		if err := l.applyMove(move, seatLabel(seat)); err != nil {
+			logger.Warn("rejecting move", "lobby_id", l.Passphrase, "move", move, "move_number", moveNumber+1, "error", err)
+			continue
+		}
+
+		moveNumber++
+		seatIndex = (seatIndex + 1) % 2
+		l.broadcast()
+
+		logger.Info("move applied", "lobby_id", l.Passphrase, "player", seatLabel(seat), "move_number", moveNumber, "fen", l.snapshot().FEN)
+
+		if seat.Kind == SeatAI {
+			time.Sleep(3 * time.Second)
+		}
+	}
+
+	logger.Info("game over", "lobby_id", l.Passphrase, "outcome", l.outcome().String(), "move_number", moveNumber)
+	if err := store.SaveLobby(l); err != nil {
+		logger.Error("error persisting final state", "lobby_id", l.Passphrase, "error", err)
+	}
+}
+
+// renewLobbyLockLoop periodically extends this replica's lease on a lobby's
+// play loop, independent of how often moves happen — a human seat can leave
+// long gaps between moves, and renewing only on move completion would let
+// the lease lapse mid-wait. It signals lostLock and exits as soon as a
+// renewal reports another replica now owns the lock.
+func (l *Lobby) renewLobbyLockLoop(lostLock chan struct{}, stop chan struct{}) {
+	ticker := time.NewTicker(renewLobbyLockInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			renewed, err := store.RenewLobbyLock(l.Passphrase, processID, lobbyLockTTL)
+			if err != nil {
+				logger.Error("error renewing lobby lock", "lobby_id", l.Passphrase, "error", err)
+				continue
+			}
+			if !renewed {
+				close(lostLock)
+				return
+			}
+		}
+	}
+}
+
+func (l *Lobby) getAIMove(seat *Seat) (string, error) {
+	l.mu.Lock()
+	game := l.Game.Clone()
+	moveHistory := append([]string(nil), l.MoveHistory...)
+	l.mu.Unlock()
+	return getValidatedMove(seat.Provider, game, moveHistory)
+}
+
+// getValidatedMove asks provider for a move against the given position,
+// trying up to 3 times (telling the provider about its last invalid move
+// on each retry) until a legal move comes back.
+func getValidatedMove(provider string, game *chess.Game, moveHistory []string) (string, error) {
+	fen := game.FEN()
+	var lastInvalidMove string
+	for attempts := 0; attempts < 3; attempts++ {
+		start := time.Now()
+		move, err := getMove(provider, fen, moveHistory, attempts > 0, lastInvalidMove)
+		latency := time.Since(start)
+		llmRequestDuration.WithLabelValues(provider).Observe(latency.Seconds())
+
+		if err != nil {
+			logger.Error("error getting move from provider", "provider", provider, "attempt", attempts+1, "latency_ms", latency.Milliseconds(), "error", err)
+			continue
+		}
+
+		if err := validateMove(game, move); err != nil {
+			invalidMoveTotal.WithLabelValues(provider).Inc()
+			movesTotal.WithLabelValues(provider, "invalid").Inc()
+			logger.Warn("provider proposed an invalid move", "provider", provider, "attempt", attempts+1, "move", move, "fen", fen)
+			lastInvalidMove = move
+			continue
+		}
+
+		movesTotal.WithLabelValues(provider, "valid").Inc()
+		logger.Info("got move from provider", "provider", provider, "attempt", attempts+1, "latency_ms", latency.Milliseconds(), "fen", fen)
+		return move, nil
+	}
+
+	return "", fmt.Errorf("failed to get a valid move from %s after 3 attempts", provider)
+}
+
+// waitForHumanMove blocks on the seat's move channel until a move arrives
+// or abort is closed. abort is closed by playLobby when it stops waiting on
+// this call's result (lock lost, or a control message preempted this seat's
+// turn), so this goroutine doesn't leak parked on moveCh forever with no
+// sender left to wake it.
+func (l *Lobby) waitForHumanMove(seatIndex int, abort <-chan struct{}) (string, error) {
+	var move string
+	select {
+	case move = <-l.moveCh[seatIndex]:
+	case <-abort:
+		return "", errPlayLoopAborted
+	}
+
+	l.mu.Lock()
+	err := validateMove(l.Game, move)
+	l.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	return move, nil
+}
+
+// outcome reads the game's current outcome under l.mu.
+func (l *Lobby) outcome() chess.Outcome {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.Game.Outcome()
+}
+
+func (l *Lobby) applyMove(move, player string) error {
+	l.mu.Lock()
+	if err := l.Game.MoveStr(move); err != nil {
+		l.mu.Unlock()
+		return err
+	}
+	l.LastMove = move
+	l.MoveHistory = append(l.MoveHistory, move)
+	l.mu.Unlock()
+
+	return store.SaveLobby(l)
+}
+
+// gameSnapshot is an atomic read of everything derived from the shared
+// *chess.Game that broadcast and persistence need, taken under l.mu since
+// notnil/chess.Game isn't safe for concurrent use.
+type gameSnapshot struct {
+	FEN         string
+	Outcome     string
+	LastMove    string
+	MoveHistory []string
+	LastSeat    int
+}
+
+func (l *Lobby) snapshot() gameSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.snapshotLocked()
+}
+
+// snapshotLocked is snapshot's body for callers that already hold l.mu.
+func (l *Lobby) snapshotLocked() gameSnapshot {
+	lastSeat := 0
+	if l.Game.Position().Turn() == chess.White {
+		lastSeat = 1
+	}
+	return gameSnapshot{
+		FEN:         l.Game.FEN(),
+		Outcome:     l.Game.Outcome().String(),
+		LastMove:    l.LastMove,
+		MoveHistory: append([]string(nil), l.MoveHistory...),
+		LastSeat:    lastSeat,
+	}
+}
+
+// broadcast publishes the current position as the lobby's latest state.
+// It doesn't touch the local ring buffer or subscriber map directly —
+// watchStore does that for every replica, including this one, once the
+// published state comes back around through the store.
+func (l *Lobby) broadcast() {
+	snap := l.snapshot()
+	state := &GameState{
+		FEN:         snap.FEN,
+		LastMove:    snap.LastMove,
+		LastPlayer:  seatLabel(l.Seats[snap.LastSeat]),
+		MoveHistory: snap.MoveHistory,
+		GameOutcome: snap.Outcome,
+		CreatedAt:   time.Now(),
+	}
+	if err := store.Publish(l.Passphrase, state); err != nil {
+		logger.Error("error publishing lobby state", "lobby_id", l.Passphrase, "error", err)
+	}
+}
+
+// watchStore subscribes to every state published for this lobby — by any
+// replica — for as long as the lobby exists, and is what actually drives
+// the local ring buffer and stream fan-out that subscribe/unsubscribe and
+// the SSE handler rely on.
+func (l *Lobby) watchStore() {
+	updates, cancel := store.Subscribe(l.Passphrase)
+	defer cancel()
+
+	for state := range updates {
+		ev := l.events.append(state)
+
+		l.subsMu.Lock()
+		if len(l.subs) == 0 {
+			logger.Info("no stream subscribers, buffered event", "lobby_id", l.Passphrase, "seq", ev.Seq)
+		}
+		for ch := range l.subs {
+			select {
+			case ch <- ev:
+			default:
+				logger.Warn("subscriber channel full, dropping event", "lobby_id", l.Passphrase, "seq", ev.Seq)
+			}
+		}
+		l.subsMu.Unlock()
+	}
+}
+
+// subscribe registers a new stream connection and returns the channel it
+// should read live events from.
+func (l *Lobby) subscribe() chan streamEvent {
+	ch := make(chan streamEvent, 16)
+	l.subsMu.Lock()
+	l.subs[ch] = struct{}{}
+	l.subsMu.Unlock()
+	return ch
+}
+
+func (l *Lobby) unsubscribe(ch chan streamEvent) {
+	l.subsMu.Lock()
+	delete(l.subs, ch)
+	l.subsMu.Unlock()
+	close(ch)
+}
+
+func seatLabel(seat *Seat) string {
+	if seat.Kind == SeatAI {
+		return seat.Provider
+	}
+	return seat.PlayerID
+}
+
+func validateMove(game *chess.Game, move string) error {
+	clone := game.Clone()
+	return clone.MoveStr(move)
+}
+
+func generatePassphrase() (string, error) {
+	word1 := passphraseWords[randomIndex(len(passphraseWords))]
+	word2 := passphraseWords[randomIndex(len(passphraseWords))]
+	word3 := passphraseWords[randomIndex(len(passphraseWords))]
+	return fmt.Sprintf("%s-%s-%s", word1, word2, word3), nil
+}
+
+func randomIndex(n int) int {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	v := int(b[0])<<24 | int(b[1])<<16 | int(b[2])<<8 | int(b[3])
+	if v < 0 {
+		v = -v
+	}
+	return v % n
+}
+
+func splitCSV(s string) []string {
+	return strings.Split(s, ",")
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+var passphraseWords = []string{
+	"amber", "bishop", "castle", "dawn", "ember", "falcon", "gambit", "harbor",
+	"ivory", "jasper", "knight", "lumen", "meadow", "nomad", "onyx", "pawn",
+	"quartz", "raven", "summit", "tidal", "umber", "velvet", "willow", "zenith",
+}
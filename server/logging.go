@@ -0,0 +1,12 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+// logger is the structured, JSON-handler logger used across the server so
+// operators can grep/aggregate by stable fields (lobby_id, player,
+// move_number, provider, attempt, latency_ms, fen) instead of parsing
+// free-form log lines.
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
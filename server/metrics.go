@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	movesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "moves_total",
+			Help: "Total moves proposed by a provider, by outcome (valid/invalid).",
+		},
+		[]string{"provider", "outcome"},
+	)
+
+	llmRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "llm_request_duration_seconds",
+			Help: "Latency of a single move request to a provider.",
+		},
+		[]string{"provider"},
+	)
+
+	invalidMoveTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "invalid_move_total",
+			Help: "Total illegal moves proposed by a provider.",
+		},
+		[]string{"provider"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(movesTotal, llmRequestDuration, invalidMoveTotal)
+}
+
+func setupMetrics(app *fiber.App) {
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+}
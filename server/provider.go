@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/time/rate"
+)
+
+// llmLimiter caps the rate of outbound requests to LLM providers across the
+// whole process, so a burst of new lobbies or tournaments can't hammer
+// OpenAI/Anthropic and blow the account budget.
+var llmLimiter = rate.NewLimiter(rate.Limit(5), 10)
+
+// Provider is a pluggable source of chess moves: an LLM today, but the
+// interface is narrow enough that a local llama.cpp endpoint or even a
+// Stockfish provider could implement it without touching the lobby or
+// tournament code.
+type Provider interface {
+	Name() string
+	Move(ctx context.Context, fen string, moveHistory []string, retryHint string) (san string, err error)
+}
+
+var providerRegistry = map[string]Provider{}
+
+// Register makes a provider eligible for lobby seats and tournament
+// pairings under its own name. Providers register themselves from an
+// init() in the file that implements them.
+func Register(p Provider) {
+	providerRegistry[p.Name()] = p
+}
+
+func getProvider(name string) (Provider, error) {
+	p, ok := providerRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+	return p, nil
+}
+
+// RegisteredProviders lists every registered provider name.
+func RegisteredProviders() []string {
+	names := make([]string, 0, len(providerRegistry))
+	for name := range providerRegistry {
+		names = append(names, name)
+	}
+	return names
+}
+
+// getMove looks up player in the provider registry and asks it for a move.
+// lastInvalidMove is only meaningful when isRetry is true.
+func getMove(player, fen string, moveHistory []string, isRetry bool, lastInvalidMove string) (string, error) {
+	p, err := getProvider(player)
+	if err != nil {
+		return "", err
+	}
+
+	retryHint := ""
+	if isRetry {
+		retryHint = lastInvalidMove
+	}
+
+	ctx := context.Background()
+	if err := llmLimiter.Wait(ctx); err != nil {
+		return "", fmt.Errorf("error waiting for LLM rate limiter: %v", err)
+	}
+
+	return p.Move(ctx, fen, moveHistory, retryHint)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore lets the server run as multiple replicas behind a load
+// balancer: lobby state lives in a Redis hash keyed by passphrase, and
+// moves propagate to every replica's SSE clients over Redis Pub/Sub on
+// channel "lobby:<phrase>" instead of an in-process channel.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func newRedisStore(addr string) (*RedisStore, error) {
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("error pinging redis at %s: %v", addr, err)
+	}
+
+	return &RedisStore{client: client}, nil
+}
+
+func lobbyHashKey(passphrase string) string { return "lobby:" + passphrase }
+func lobbyChannel(passphrase string) string { return "lobby:" + passphrase }
+func lobbyLockKey(passphrase string) string { return "lobby-lock:" + passphrase }
+
+func (s *RedisStore) SaveLobby(l *Lobby) error {
+	seatsJSON, err := json.Marshal(l.Seats)
+	if err != nil {
+		return err
+	}
+	snap := l.snapshot()
+
+	return s.client.HSet(context.Background(), lobbyHashKey(l.Passphrase), map[string]interface{}{
+		"seats":        string(seatsJSON),
+		"fen":          snap.FEN,
+		"last_move":    snap.LastMove,
+		"move_history": strings.Join(snap.MoveHistory, ","),
+		"outcome":      snap.Outcome,
+	}).Err()
+}
+
+func (s *RedisStore) LoadOpenLobbies() ([]lobbySnapshot, error) {
+	ctx := context.Background()
+
+	// KEYS scans the whole keyspace in one blocking call, which is fine for
+	// a one-off debug command but not for a hot path in the feature whose
+	// entire purpose is running many replicas against a shared Redis — do
+	// the same walk incrementally with SCAN instead.
+	var keys []string
+	var cursor uint64
+	for {
+		batch, next, err := s.client.Scan(ctx, cursor, "lobby:*", 0).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, batch...)
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	var snapshots []lobbySnapshot
+	for _, key := range keys {
+		fields, err := s.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		if outcome := fields["outcome"]; outcome != "" && outcome != "*" {
+			continue
+		}
+
+		var seats [2]*Seat
+		if err := json.Unmarshal([]byte(fields["seats"]), &seats); err != nil {
+			logger.Error("error restoring lobby from redis", "key", key, "error", err)
+			continue
+		}
+
+		snapshots = append(snapshots, lobbySnapshot{
+			Passphrase:     strings.TrimPrefix(key, "lobby:"),
+			Seats:          seats,
+			MoveHistoryCSV: fields["move_history"],
+		})
+	}
+
+	return snapshots, nil
+}
+
+func (s *RedisStore) Publish(passphrase string, state *GameState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(context.Background(), lobbyChannel(passphrase), data).Err()
+}
+
+func (s *RedisStore) Subscribe(passphrase string) (<-chan *GameState, func()) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	pubsub := s.client.Subscribe(ctx, lobbyChannel(passphrase))
+
+	out := make(chan *GameState, 16)
+	go func() {
+		defer close(out)
+		for msg := range pubsub.Channel() {
+			var state GameState
+			if err := json.Unmarshal([]byte(msg.Payload), &state); err != nil {
+				logger.Error("error decoding redis pubsub message", "lobby_id", passphrase, "error", err)
+				continue
+			}
+			select {
+			case out <- &state:
+			default:
+				logger.Warn("subscriber channel full, dropping event", "lobby_id", passphrase)
+			}
+		}
+	}()
+
+	cancel := func() {
+		cancelCtx()
+		pubsub.Close()
+	}
+
+	return out, cancel
+}
+
+// AcquireLobbyLock uses SET NX PX to become the sole replica driving a
+// lobby's play loop.
+func (s *RedisStore) AcquireLobbyLock(passphrase, owner string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(context.Background(), lobbyLockKey(passphrase), owner, ttl).Result()
+}
+
+// renewLockScript only extends the lease if this owner still holds it, so
+// a replica that lost the lock (its lease expired and another replica took
+// over) can't accidentally steal it back mid-handover.
+const renewLockScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+else
+	return 0
+end
+`
+
+func (s *RedisStore) RenewLobbyLock(passphrase, owner string, ttl time.Duration) (bool, error) {
+	result, err := s.client.Eval(context.Background(), renewLockScript,
+		[]string{lobbyLockKey(passphrase)}, owner, ttl.Milliseconds()).Result()
+	if err != nil {
+		return false, err
+	}
+
+	renewed, _ := result.(int64)
+	return renewed == 1, nil
+}
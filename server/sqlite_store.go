@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQLiteStore is the original single-process behavior: lobbies persist to
+// the same SQLite database as everything else, and pub/sub and locking are
+// just in-memory, since there is only ever one replica to coordinate with.
+type SQLiteStore struct {
+	subsMu sync.Mutex
+	subs   map[string]map[chan *GameState]struct{}
+}
+
+func newSQLiteStore() *SQLiteStore {
+	return &SQLiteStore{subs: make(map[string]map[chan *GameState]struct{})}
+}
+
+func (s *SQLiteStore) SaveLobby(l *Lobby) error {
+	if db == nil {
+		return nil
+	}
+
+	seatsJSON, err := json.Marshal(l.Seats)
+	if err != nil {
+		return err
+	}
+	snap := l.snapshot()
+
+	_, err = db.Exec(`INSERT INTO lobbies (passphrase, seats, fen, last_move, move_history, game_outcome)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(passphrase) DO UPDATE SET
+			seats = excluded.seats,
+			fen = excluded.fen,
+			last_move = excluded.last_move,
+			move_history = excluded.move_history,
+			game_outcome = excluded.game_outcome`,
+		l.Passphrase, string(seatsJSON), snap.FEN, snap.LastMove, strings.Join(snap.MoveHistory, ","), snap.Outcome)
+	if err != nil {
+		logger.Error("error saving lobby", "lobby_id", l.Passphrase, "error", err)
+	}
+	return err
+}
+
+func (s *SQLiteStore) LoadOpenLobbies() ([]lobbySnapshot, error) {
+	if db == nil {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`SELECT passphrase, seats, move_history FROM lobbies WHERE game_outcome = '' OR game_outcome IS NULL OR game_outcome = '*'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []lobbySnapshot
+	for rows.Next() {
+		var passphrase, seatsJSON, moveHistoryCSV string
+		if err := rows.Scan(&passphrase, &seatsJSON, &moveHistoryCSV); err != nil {
+			return nil, err
+		}
+
+		var seats [2]*Seat
+		if err := json.Unmarshal([]byte(seatsJSON), &seats); err != nil {
+			logger.Error("error restoring lobby", "lobby_id", passphrase, "error", err)
+			continue
+		}
+
+		snapshots = append(snapshots, lobbySnapshot{
+			Passphrase:     passphrase,
+			Seats:          seats,
+			MoveHistoryCSV: moveHistoryCSV,
+		})
+	}
+
+	return snapshots, rows.Err()
+}
+
+func (s *SQLiteStore) Publish(passphrase string, state *GameState) error {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	for ch := range s.subs[passphrase] {
+		select {
+		case ch <- state:
+		default:
+			logger.Warn("subscriber channel full, dropping event", "lobby_id", passphrase)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Subscribe(passphrase string) (<-chan *GameState, func()) {
+	ch := make(chan *GameState, 16)
+
+	s.subsMu.Lock()
+	if s.subs[passphrase] == nil {
+		s.subs[passphrase] = make(map[chan *GameState]struct{})
+	}
+	s.subs[passphrase][ch] = struct{}{}
+	s.subsMu.Unlock()
+
+	cancel := func() {
+		s.subsMu.Lock()
+		delete(s.subs[passphrase], ch)
+		s.subsMu.Unlock()
+		close(ch)
+	}
+
+	return ch, cancel
+}
+
+// A single process has no one to contend with, so the lock is always free.
+func (s *SQLiteStore) AcquireLobbyLock(passphrase, owner string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (s *SQLiteStore) RenewLobbyLock(passphrase, owner string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
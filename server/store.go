@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// lobbySnapshot is everything needed to rebuild an in-memory Lobby for an
+// unfinished game, as read back from a Store on startup.
+type lobbySnapshot struct {
+	Passphrase     string
+	Seats          [2]*Seat
+	MoveHistoryCSV string
+}
+
+// Store abstracts lobby persistence, cross-replica event propagation, and
+// the per-lobby lock that ensures only one replica drives a given lobby's
+// play loop at a time. Without this, running two replicas behind a load
+// balancer would split-brain a lobby's game and break its SSE stream for
+// clients connected to the other replica.
+type Store interface {
+	SaveLobby(l *Lobby) error
+	LoadOpenLobbies() ([]lobbySnapshot, error)
+
+	// Publish broadcasts a lobby's latest state to every replica watching
+	// it (including this one).
+	Publish(passphrase string, state *GameState) error
+	// Subscribe returns every state published for passphrase from now on,
+	// plus a cancel func to stop watching and release resources.
+	Subscribe(passphrase string) (<-chan *GameState, func())
+
+	// AcquireLobbyLock attempts to become the replica that drives
+	// passphrase's play loop, holding the lease for ttl.
+	AcquireLobbyLock(passphrase, owner string, ttl time.Duration) (bool, error)
+	// RenewLobbyLock extends an already-held lease. It returns false if
+	// another replica now owns the lock (the lease expired and was taken
+	// over), in which case this replica must stop driving the game.
+	RenewLobbyLock(passphrase, owner string, ttl time.Duration) (bool, error)
+}
+
+var store Store
+
+// processID identifies this replica as the owner in lobby locks.
+var processID string
+
+func initStore() {
+	id, err := newUUID()
+	if err != nil {
+		logger.Error("error generating process id", "error", err)
+		os.Exit(1)
+	}
+	processID = id
+
+	switch backend := os.Getenv("STORE_BACKEND"); backend {
+	case "", "sqlite":
+		store = newSQLiteStore()
+	case "redis":
+		s, err := newRedisStore(os.Getenv("REDIS_ADDR"))
+		if err != nil {
+			logger.Error("error connecting to redis", "error", err)
+			os.Exit(1)
+		}
+		store = s
+	default:
+		logger.Error("unknown STORE_BACKEND", "backend", backend)
+		os.Exit(1)
+	}
+}
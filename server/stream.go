@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	eventLogCapacity = 512
+	keepaliveEvery   = 15 * time.Second
+	writeDeadline    = 5 * time.Second
+)
+
+// streamEvent is one broadcast game-state change, numbered with a
+// monotonically increasing sequence id so reconnecting clients can ask for
+// everything after the last one they saw.
+type streamEvent struct {
+	Seq   uint64
+	State *GameState
+}
+
+// eventLog is a bounded, per-lobby ring buffer of the most recent stream
+// events, used to replay missed events to a client reconnecting with a
+// Last-Event-ID header.
+type eventLog struct {
+	mu      sync.Mutex
+	events  []streamEvent
+	nextSeq uint64
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{}
+}
+
+func (e *eventLog) append(state *GameState) streamEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.nextSeq++
+	ev := streamEvent{Seq: e.nextSeq, State: state}
+
+	e.events = append(e.events, ev)
+	if len(e.events) > eventLogCapacity {
+		e.events = e.events[len(e.events)-eventLogCapacity:]
+	}
+
+	return ev
+}
+
+// since returns every buffered event with a sequence id greater than after,
+// oldest first.
+func (e *eventLog) since(after uint64) []streamEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]streamEvent, 0, len(e.events))
+	for _, ev := range e.events {
+		if ev.Seq > after {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func handleLobbyStream(c *fiber.Ctx) error {
+	phrase := c.Params("phrase")
+	lobby, ok := lobbies.Get(phrase)
+	if !ok {
+		return fiber.ErrNotFound
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("Transfer-Encoding", "chunked")
+
+	var after uint64
+	if lastEventID := c.Get("Last-Event-ID"); lastEventID != "" {
+		if parsed, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+			after = parsed
+		}
+	}
+
+	ctx := c.Context()
+	if ctx == nil {
+		return fiber.ErrInternalServerError
+	}
+
+	logger.Info("stream connection established", "lobby_id", phrase, "last_event_id", after)
+
+	sub := lobby.subscribe()
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer lobby.unsubscribe(sub)
+
+		for _, ev := range lobby.events.since(after) {
+			if !writeStreamEvent(w, ctx, ev) {
+				return
+			}
+		}
+
+		ticker := time.NewTicker(keepaliveEvery)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case ev, ok := <-sub:
+				if !ok {
+					logger.Info("subscriber channel closed", "lobby_id", phrase)
+					return
+				}
+				if !writeStreamEvent(w, ctx, ev) {
+					return
+				}
+			case <-ticker.C:
+				if !writeKeepalive(w, ctx) {
+					return
+				}
+			case <-ctx.Done():
+				logger.Info("stream connection closed", "lobby_id", phrase)
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+func writeStreamEvent(w *bufio.Writer, ctx *fasthttp.RequestCtx, ev streamEvent) bool {
+	data, err := json.Marshal(ev.State)
+	if err != nil {
+		logger.Error("error marshaling event", "seq", ev.Seq, "error", err)
+		return true
+	}
+
+	return writeWithDeadline(w, ctx, func() error {
+		if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.Seq, data); err != nil {
+			return err
+		}
+		return w.Flush()
+	})
+}
+
+func writeKeepalive(w *bufio.Writer, ctx *fasthttp.RequestCtx) bool {
+	return writeWithDeadline(w, ctx, func() error {
+		if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+			return err
+		}
+		return w.Flush()
+	})
+}
+
+// writeWithDeadline bounds how long a single write may block on a slow or
+// dead client by setting a deadline directly on the underlying connection,
+// then issuing the write on the calling goroutine — the same goroutine
+// fasthttp's SetBodyStreamWriter handed w to. An earlier version ran the
+// write in a spawned goroutine and raced it against a timer, returning
+// false (and letting the stream handler tear the connection down) while
+// that goroutine was still blocked inside write(); once fasthttp recycled
+// the connection/buffer, the abandoned goroutine could still write into
+// it. Setting the deadline on the connection itself means a hung write
+// fails on its own — via the ordinary net.Conn deadline mechanism — so
+// there's never a second goroutine contending for w.
+func writeWithDeadline(w *bufio.Writer, ctx *fasthttp.RequestCtx, write func() error) bool {
+	if conn := ctx.Conn(); conn != nil {
+		if err := conn.SetWriteDeadline(time.Now().Add(writeDeadline)); err != nil {
+			logger.Error("error setting SSE write deadline", "error", err)
+			return false
+		}
+	}
+
+	if err := write(); err != nil {
+		logger.Error("error writing to SSE stream", "error", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	eloK           = 32.0
+	startingRating = 1200.0
+)
+
+// Pairing is one scheduled game within a tournament.
+type Pairing struct {
+	White string
+	Black string
+}
+
+// RoundRobinPairings schedules every provider against every other provider
+// with both colors, so a tournament of N providers plays N*(N-1) games.
+func RoundRobinPairings(providers []string) []Pairing {
+	pairings := make([]Pairing, 0, len(providers)*(len(providers)-1))
+	for _, white := range providers {
+		for _, black := range providers {
+			if white == black {
+				continue
+			}
+			pairings = append(pairings, Pairing{White: white, Black: black})
+		}
+	}
+	return pairings
+}
+
+// Tournament is a round-robin run over a fixed set of registered providers.
+type Tournament struct {
+	ID        string
+	Providers []string
+	CreatedAt time.Time
+}
+
+// NewTournament creates and persists a tournament, then runs every pairing
+// in the background. It returns immediately with the tournament's ID.
+func NewTournament(providers []string) (*Tournament, error) {
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tournament{
+		ID:        id,
+		Providers: providers,
+		CreatedAt: time.Now(),
+	}
+
+	if err := saveTournament(t); err != nil {
+		return nil, err
+	}
+
+	go t.run()
+
+	return t, nil
+}
+
+func (t *Tournament) run() {
+	for _, pairing := range RoundRobinPairings(t.Providers) {
+		outcome, moveCount, pgn, err := playTournamentGame(pairing)
+		if err != nil {
+			logger.Error("error playing tournament game", "tournament_id", t.ID, "white", pairing.White, "black", pairing.Black, "error", err)
+			continue
+		}
+
+		if err := recordTournamentGame(t.ID, pairing, outcome, moveCount, pgn); err != nil {
+			logger.Error("error recording tournament game", "tournament_id", t.ID, "white", pairing.White, "black", pairing.Black, "error", err)
+		}
+
+		if err := applyEloUpdate(pairing, outcome); err != nil {
+			logger.Error("error updating ratings", "tournament_id", t.ID, "white", pairing.White, "black", pairing.Black, "error", err)
+		}
+	}
+
+	logger.Info("tournament finished", "tournament_id", t.ID)
+}
+
+// playTournamentGame runs a single AI-vs-AI game to completion outside of
+// the lobby system (a tournament game has no human seats and no stream
+// subscribers) and returns the outcome, move count, and PGN of the
+// completed game.
+func playTournamentGame(pairing Pairing) (outcome string, moveCount int, pgn string, err error) {
+	game := chess.NewGame()
+	moveHistory := []string{}
+	players := [2]string{pairing.White, pairing.Black}
+	seatIndex := 0
+
+	for game.Outcome() == chess.NoOutcome {
+		move, err := getValidatedMove(players[seatIndex], game, moveHistory)
+		if err != nil {
+			return "", 0, "", err
+		}
+
+		if err := game.MoveStr(move); err != nil {
+			return "", 0, "", fmt.Errorf("applying already-validated move %q: %v", move, err)
+		}
+
+		moveHistory = append(moveHistory, move)
+		seatIndex = (seatIndex + 1) % 2
+	}
+
+	return game.Outcome().String(), len(moveHistory), game.String(), nil
+}
+
+// whiteScore converts a chess.Outcome string into white's score for ELO
+// purposes: 1 for a win, 0.5 for a draw, 0 for a loss.
+func whiteScore(outcome string) float64 {
+	switch outcome {
+	case "1-0":
+		return 1
+	case "0-1":
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+func expectedScore(ra, rb float64) float64 {
+	return 1.0 / (1.0 + math.Pow(10, (rb-ra)/400))
+}
+
+// applyEloUpdate adjusts both providers' ratings after a completed game
+// using the standard R' = R + K*(S - E) formula with K=32.
+func applyEloUpdate(pairing Pairing, outcome string) error {
+	ratingWhite, err := getOrInitRating(pairing.White)
+	if err != nil {
+		return err
+	}
+	ratingBlack, err := getOrInitRating(pairing.Black)
+	if err != nil {
+		return err
+	}
+
+	scoreWhite := whiteScore(outcome)
+	scoreBlack := 1 - scoreWhite
+
+	expectedWhite := expectedScore(ratingWhite, ratingBlack)
+	expectedBlack := 1 - expectedWhite
+
+	newRatingWhite := ratingWhite + eloK*(scoreWhite-expectedWhite)
+	newRatingBlack := ratingBlack + eloK*(scoreBlack-expectedBlack)
+
+	return updateRatingsAfterGame(pairing.White, newRatingWhite, scoreWhite, pairing.Black, newRatingBlack, scoreBlack)
+}
+
+// ProviderStanding is one row of a tournament's standings table.
+type ProviderStanding struct {
+	Provider        string  `json:"provider"`
+	Rating          float64 `json:"rating"`
+	Wins            int     `json:"wins"`
+	Losses          int     `json:"losses"`
+	Draws           int     `json:"draws"`
+	AvgMovesPerGame float64 `json:"avgMovesPerGame"`
+}
+
+func tournamentStandings(tournamentID string) ([]ProviderStanding, error) {
+	rows, err := db.Query(`SELECT white, black, outcome, move_count FROM tournament_games WHERE tournament_id = ?`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type tally struct {
+		wins, losses, draws, games, totalMoves int
+	}
+	tallies := map[string]*tally{}
+
+	ensure := func(provider string) *tally {
+		if _, ok := tallies[provider]; !ok {
+			tallies[provider] = &tally{}
+		}
+		return tallies[provider]
+	}
+
+	for rows.Next() {
+		var white, black, outcome string
+		var moveCount int
+		if err := rows.Scan(&white, &black, &outcome, &moveCount); err != nil {
+			return nil, err
+		}
+
+		whiteTally, blackTally := ensure(white), ensure(black)
+		whiteTally.games++
+		blackTally.games++
+		whiteTally.totalMoves += moveCount
+		blackTally.totalMoves += moveCount
+
+		switch outcome {
+		case "1-0":
+			whiteTally.wins++
+			blackTally.losses++
+		case "0-1":
+			whiteTally.losses++
+			blackTally.wins++
+		default:
+			whiteTally.draws++
+			blackTally.draws++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	standings := make([]ProviderStanding, 0, len(tallies))
+	for provider, t := range tallies {
+		rating, err := getOrInitRating(provider)
+		if err != nil {
+			return nil, err
+		}
+
+		avgMoves := 0.0
+		if t.games > 0 {
+			avgMoves = float64(t.totalMoves) / float64(t.games)
+		}
+
+		standings = append(standings, ProviderStanding{
+			Provider:        provider,
+			Rating:          rating,
+			Wins:            t.wins,
+			Losses:          t.losses,
+			Draws:           t.draws,
+			AvgMovesPerGame: avgMoves,
+		})
+	}
+
+	return standings, nil
+}
+
+func saveTournament(t *Tournament) error {
+	_, err := db.Exec(`INSERT INTO tournaments (id, providers) VALUES (?, ?)`,
+		t.ID, strings.Join(t.Providers, ","))
+	return err
+}
+
+func recordTournamentGame(tournamentID string, pairing Pairing, outcome string, moveCount int, pgn string) error {
+	_, err := db.Exec(`INSERT INTO tournament_games (tournament_id, white, black, outcome, move_count, pgn) VALUES (?, ?, ?, ?, ?, ?)`,
+		tournamentID, pairing.White, pairing.Black, outcome, moveCount, pgn)
+	return err
+}
+
+func getOrInitRating(provider string) (float64, error) {
+	var rating float64
+	err := db.QueryRow(`SELECT rating FROM provider_ratings WHERE provider = ?`, provider).Scan(&rating)
+	if err == sql.ErrNoRows {
+		_, err = db.Exec(`INSERT INTO provider_ratings (provider, rating) VALUES (?, ?)`, provider, startingRating)
+		return startingRating, err
+	}
+	return rating, err
+}
+
+func updateRatingsAfterGame(white string, whiteRating, whiteScore float64, black string, blackRating, blackScore float64) error {
+	if err := upsertRating(white, whiteRating, whiteScore); err != nil {
+		return err
+	}
+	return upsertRating(black, blackRating, blackScore)
+}
+
+func upsertRating(provider string, rating, score float64) error {
+	wins, losses, draws := 0, 0, 0
+	switch score {
+	case 1:
+		wins = 1
+	case 0:
+		losses = 1
+	default:
+		draws = 1
+	}
+
+	_, err := db.Exec(`INSERT INTO provider_ratings (provider, rating, wins, losses, draws, games)
+		VALUES (?, ?, ?, ?, ?, 1)
+		ON CONFLICT(provider) DO UPDATE SET
+			rating = excluded.rating,
+			wins = provider_ratings.wins + excluded.wins,
+			losses = provider_ratings.losses + excluded.losses,
+			draws = provider_ratings.draws + excluded.draws,
+			games = provider_ratings.games + 1`,
+		provider, rating, wins, losses, draws)
+	return err
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type createTournamentRequest struct {
+	Providers []string `json:"providers"`
+}
+
+func handleCreateTournament(c *fiber.Ctx) error {
+	var req createTournamentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+
+	if len(req.Providers) < 2 {
+		return fiber.NewError(fiber.StatusBadRequest, "a tournament needs at least 2 providers")
+	}
+	for _, name := range req.Providers {
+		if _, err := getProvider(name); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("unknown provider: %s", name))
+		}
+	}
+
+	t, err := NewTournament(req.Providers)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(fiber.Map{"id": t.ID})
+}
+
+func handleTournamentStandings(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	standings, err := tournamentStandings(id)
+	if err != nil {
+		return fiber.ErrInternalServerError
+	}
+
+	return c.JSON(fiber.Map{"standings": standings})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	wsWriteDeadline = 5 * time.Second
+	wsPingEvery     = 20 * time.Second
+	wsPongWait      = 10 * time.Second
+)
+
+// wsClientMessage is one frame sent by the browser over the lobby's
+// WebSocket connection.
+type wsClientMessage struct {
+	Type        string `json:"type"`
+	San         string `json:"san,omitempty"`
+	PlayerToken string `json:"playerToken,omitempty"`
+}
+
+// wsErrorMessage tells the client a message it sent was rejected, since
+// there's no HTTP status code to carry that over a WebSocket frame.
+type wsErrorMessage struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// setupWebSocket wires the lobby WebSocket endpoint, which lets a human
+// player both receive game-state pushes and submit moves/resignations/draw
+// offers over a single connection instead of pairing the SSE stream with a
+// separate POST per move. The SSE endpoint stays up as a read-only fallback
+// for clients behind a proxy that blocks WebSocket upgrades.
+func setupWebSocket(app *fiber.App) {
+	app.Use("/api/lobbies/:phrase/ws", func(c *fiber.Ctx) error {
+		if websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		return fiber.ErrUpgradeRequired
+	})
+	app.Get("/api/lobbies/:phrase/ws", websocket.New(handleLobbyWS))
+}
+
+func handleLobbyWS(c *websocket.Conn) {
+	phrase := c.Params("phrase")
+	lobby, ok := lobbies.Get(phrase)
+	if !ok {
+		c.Close()
+		return
+	}
+
+	logger.Info("ws connection established", "lobby_id", phrase)
+	defer logger.Info("ws connection closed", "lobby_id", phrase)
+
+	var writeMu sync.Mutex
+	write := func(messageType int, data []byte) bool {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		c.SetWriteDeadline(time.Now().Add(wsWriteDeadline))
+		if err := c.WriteMessage(messageType, data); err != nil {
+			logger.Warn("error writing to ws client", "lobby_id", phrase, "error", err)
+			return false
+		}
+		return true
+	}
+	writeJSON := func(v interface{}) bool {
+		data, err := json.Marshal(v)
+		if err != nil {
+			logger.Error("error marshaling ws message", "lobby_id", phrase, "error", err)
+			return true
+		}
+		return write(websocket.TextMessage, data)
+	}
+
+	pong := make(chan struct{}, 1)
+	c.SetPongHandler(func(string) error {
+		select {
+		case pong <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	sub := lobby.subscribe()
+	defer lobby.unsubscribe(sub)
+
+	reads := make(chan wsClientMessage)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var msg wsClientMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				logger.Warn("error decoding ws message", "lobby_id", phrase, "error", err)
+				continue
+			}
+
+			select {
+			case reads <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	snap := lobby.snapshot()
+	initial := &GameState{
+		FEN:         snap.FEN,
+		LastMove:    snap.LastMove,
+		MoveHistory: snap.MoveHistory,
+		GameOutcome: snap.Outcome,
+		CreatedAt:   lobby.CreatedAt,
+	}
+	if !writeJSON(initial) {
+		return
+	}
+
+	pingTicker := time.NewTicker(wsPingEvery)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case msg := <-reads:
+			handleWSClientMessage(lobby, phrase, msg, writeJSON)
+
+		case ev, ok := <-sub:
+			if !ok {
+				return
+			}
+			if !writeJSON(ev.State) {
+				return
+			}
+
+		case <-pingTicker.C:
+			if !write(websocket.PingMessage, nil) {
+				return
+			}
+			select {
+			case <-pong:
+			case <-time.After(wsPongWait):
+				logger.Warn("ws client missed pong, dropping connection", "lobby_id", phrase)
+				return
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+func handleWSClientMessage(lobby *Lobby, phrase string, msg wsClientMessage, writeJSON func(v interface{}) bool) {
+	switch msg.Type {
+	case "move":
+		lock := lobby.playerLock(msg.PlayerToken)
+		lock.Lock()
+		err := lobby.SubmitMove(msg.PlayerToken, msg.San)
+		lock.Unlock()
+		if err != nil {
+			logger.Warn("rejecting ws move", "lobby_id", phrase, "move", msg.San, "error", err)
+			writeJSON(&wsErrorMessage{Type: "error", Message: err.Error()})
+		}
+	case "resign":
+		if err := lobby.Resign(msg.PlayerToken); err != nil {
+			logger.Warn("rejecting ws resignation", "lobby_id", phrase, "error", err)
+			writeJSON(&wsErrorMessage{Type: "error", Message: err.Error()})
+		}
+	case "offer_draw":
+		if err := lobby.OfferDraw(msg.PlayerToken); err != nil {
+			logger.Warn("rejecting ws draw offer", "lobby_id", phrase, "error", err)
+			writeJSON(&wsErrorMessage{Type: "error", Message: err.Error()})
+		}
+	default:
+		logger.Warn("unknown ws message type", "lobby_id", phrase, "type", msg.Type)
+	}
+}